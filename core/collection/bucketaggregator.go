@@ -0,0 +1,301 @@
+package collection
+
+import (
+	"math"
+	"sort"
+)
+
+// MinMaxBucket tracks the smallest and largest values added to it, alongside a
+// count, so a RollingWindowG can answer "what's the min/max over the window"
+// instead of only a sum/count.
+type MinMaxBucket struct {
+	Min   float64
+	Max   float64
+	Count int64
+}
+
+type minMaxAggregator struct{}
+
+// MinMaxAggregator is a BucketAggregator that keeps the min and max of the
+// values added to each bucket.
+func MinMaxAggregator() BucketAggregator[MinMaxBucket] {
+	return minMaxAggregator{}
+}
+
+func (minMaxAggregator) Add(b *MinMaxBucket, v float64) {
+	if b.Count == 0 {
+		b.Min, b.Max = v, v
+	} else {
+		if v < b.Min {
+			b.Min = v
+		}
+		if v > b.Max {
+			b.Max = v
+		}
+	}
+	b.Count++
+}
+
+func (minMaxAggregator) Reset(b *MinMaxBucket) {
+	*b = MinMaxBucket{}
+}
+
+func (minMaxAggregator) Merge(dst *MinMaxBucket, src MinMaxBucket) {
+	if src.Count == 0 {
+		return
+	}
+	if dst.Count == 0 {
+		*dst = src
+		return
+	}
+	if src.Min < dst.Min {
+		dst.Min = src.Min
+	}
+	if src.Max > dst.Max {
+		dst.Max = src.Max
+	}
+	dst.Count += src.Count
+}
+
+// digestCentroid is a single weighted mean kept by a QuantileBucket, the same
+// building block used by t-digest/CKMS style sketches.
+type digestCentroid struct {
+	mean   float64
+	weight float64
+}
+
+// maxCentroids bounds the memory and merge cost of a QuantileBucket. Once this
+// cap is exceeded, the two closest adjacent centroids are merged into one,
+// trading a small amount of accuracy for a fixed bucket size.
+const maxCentroids = 32
+
+// QuantileBucket is a bounded digest that can estimate P50/P95/P99 (or any other
+// quantile) of the values added to it, without keeping every sample.
+type QuantileBucket struct {
+	centroids []digestCentroid
+	count     int64
+}
+
+type quantileAggregator struct{}
+
+// QuantileAggregator is a BucketAggregator that maintains a bounded digest of
+// the values added to each bucket, so Quantile can later estimate percentiles
+// such as P50/P95/P99 without retaining every sample.
+func QuantileAggregator() BucketAggregator[QuantileBucket] {
+	return quantileAggregator{}
+}
+
+func (quantileAggregator) Add(b *QuantileBucket, v float64) {
+	b.count++
+	b.centroids = append(b.centroids, digestCentroid{mean: v, weight: 1})
+	if len(b.centroids) <= maxCentroids {
+		return
+	}
+
+	sort.Slice(b.centroids, func(i, j int) bool { return b.centroids[i].mean < b.centroids[j].mean })
+	// merge the closest adjacent pair to shrink back under the cap
+	best := 0
+	bestGap := math.Inf(1)
+	for i := 0; i+1 < len(b.centroids); i++ {
+		gap := b.centroids[i+1].mean - b.centroids[i].mean
+		if gap < bestGap {
+			bestGap = gap
+			best = i
+		}
+	}
+	merged := mergeCentroids(b.centroids[best], b.centroids[best+1])
+	b.centroids = append(b.centroids[:best], b.centroids[best+1:]...)
+	b.centroids[best] = merged
+}
+
+func mergeCentroids(a, b digestCentroid) digestCentroid {
+	weight := a.weight + b.weight
+	return digestCentroid{
+		mean:   (a.mean*a.weight + b.mean*b.weight) / weight,
+		weight: weight,
+	}
+}
+
+func (quantileAggregator) Reset(b *QuantileBucket) {
+	b.centroids = nil
+	b.count = 0
+}
+
+func (quantileAggregator) Merge(dst *QuantileBucket, src QuantileBucket) {
+	dst.count += src.count
+	dst.centroids = append(dst.centroids, src.centroids...)
+	for len(dst.centroids) > maxCentroids {
+		sort.Slice(dst.centroids, func(i, j int) bool { return dst.centroids[i].mean < dst.centroids[j].mean })
+		best := 0
+		bestGap := math.Inf(1)
+		for i := 0; i+1 < len(dst.centroids); i++ {
+			gap := dst.centroids[i+1].mean - dst.centroids[i].mean
+			if gap < bestGap {
+				bestGap = gap
+				best = i
+			}
+		}
+		merged := mergeCentroids(dst.centroids[best], dst.centroids[best+1])
+		dst.centroids = append(dst.centroids[:best], dst.centroids[best+1:]...)
+		dst.centroids[best] = merged
+	}
+}
+
+// Quantile estimates the value at quantile q (0 < q <= 1), e.g. b.Quantile(0.99)
+// for P99. It returns 0 if the bucket has no samples.
+func (b QuantileBucket) Quantile(q float64) float64 {
+	if len(b.centroids) == 0 {
+		return 0
+	}
+
+	centroids := append([]digestCentroid(nil), b.centroids...)
+	sort.Slice(centroids, func(i, j int) bool { return centroids[i].mean < centroids[j].mean })
+
+	var total float64
+	for _, c := range centroids {
+		total += c.weight
+	}
+
+	target := q * total
+	var cumulative float64
+	for _, c := range centroids {
+		cumulative += c.weight
+		if cumulative >= target {
+			return c.mean
+		}
+	}
+	return centroids[len(centroids)-1].mean
+}
+
+// HistogramBucket counts values into a fixed set of Prometheus-style buckets,
+// each upper-bounded (inclusive) by the matching entry in Bounds, plus a final
+// overflow bucket. Counts are per bucket, not cumulative: each value is counted
+// only in the first bucket whose bound is >= the value.
+type HistogramBucket struct {
+	Bounds []float64
+	Counts []int64
+	Sum    float64
+	Count  int64
+}
+
+type histogramAggregator struct {
+	bounds []float64
+}
+
+// HistogramAggregator is a BucketAggregator that sorts values into buckets
+// upper-bounded by bounds, like Prometheus histogram buckets but with
+// non-cumulative counts, see HistogramBucket.
+// bounds must be sorted ascending; a final +Inf bucket is implicit.
+func HistogramAggregator(bounds []float64) BucketAggregator[HistogramBucket] {
+	return histogramAggregator{bounds: bounds}
+}
+
+func (h histogramAggregator) Add(b *HistogramBucket, v float64) {
+	if b.Bounds == nil {
+		b.Bounds = h.bounds
+		b.Counts = make([]int64, len(h.bounds)+1)
+	}
+	b.Sum += v
+	b.Count++
+	idx := sort.SearchFloat64s(h.bounds, v)
+	b.Counts[idx]++
+}
+
+func (h histogramAggregator) Reset(b *HistogramBucket) {
+	b.Sum = 0
+	b.Count = 0
+	for i := range b.Counts {
+		b.Counts[i] = 0
+	}
+}
+
+func (h histogramAggregator) Merge(dst *HistogramBucket, src HistogramBucket) {
+	if src.Count == 0 {
+		return
+	}
+	if dst.Bounds == nil {
+		dst.Bounds = h.bounds
+		dst.Counts = make([]int64, len(h.bounds)+1)
+	}
+	dst.Sum += src.Sum
+	dst.Count += src.Count
+	for i, c := range src.Counts {
+		dst.Counts[i] += c
+	}
+}
+
+// LatencyBucket is an HdrHistogram-like aggregator for latency values: it
+// buckets values logarithmically between the lowest and highest bounds given
+// to LatencyAggregator, giving roughly
+// constant relative error across a wide dynamic range instead of the fixed
+// absolute-width buckets a plain HistogramBucket uses.
+type LatencyBucket struct {
+	Bounds []float64
+	Counts []int64
+	Sum    float64
+	Count  int64
+}
+
+type latencyAggregator struct {
+	bounds []float64
+}
+
+// LatencyAggregator is a BucketAggregator that buckets values logarithmically
+// between lowest and highest with 10^(significantFigures-1) buckets per decade
+// (each bound is 10^(1/10^(significantFigures-1)) times the previous one),
+// similar to HdrHistogram's fixed-precision buckets.
+// Values outside [lowest, highest] are clamped into the first/last bucket.
+func LatencyAggregator(lowest, highest float64, significantFigures int) BucketAggregator[LatencyBucket] {
+	if lowest <= 0 {
+		lowest = 1
+	}
+	if significantFigures < 1 {
+		significantFigures = 2
+	}
+
+	ratio := math.Pow(10, 1/math.Pow(10, float64(significantFigures-1)))
+	var bounds []float64
+	for v := lowest; v < highest; v *= ratio {
+		bounds = append(bounds, v)
+	}
+	bounds = append(bounds, highest)
+
+	return latencyAggregator{bounds: bounds}
+}
+
+func (l latencyAggregator) Add(b *LatencyBucket, v float64) {
+	if b.Bounds == nil {
+		b.Bounds = l.bounds
+		b.Counts = make([]int64, len(l.bounds)+1)
+	}
+	b.Sum += v
+	b.Count++
+	idx := sort.SearchFloat64s(l.bounds, v)
+	if idx >= len(b.Counts) {
+		idx = len(b.Counts) - 1
+	}
+	b.Counts[idx]++
+}
+
+func (l latencyAggregator) Reset(b *LatencyBucket) {
+	b.Sum = 0
+	b.Count = 0
+	for i := range b.Counts {
+		b.Counts[i] = 0
+	}
+}
+
+func (l latencyAggregator) Merge(dst *LatencyBucket, src LatencyBucket) {
+	if src.Count == 0 {
+		return
+	}
+	if dst.Bounds == nil {
+		dst.Bounds = l.bounds
+		dst.Counts = make([]int64, len(l.bounds)+1)
+	}
+	dst.Sum += src.Sum
+	dst.Count += src.Count
+	for i, c := range src.Counts {
+		dst.Counts[i] += c
+	}
+}
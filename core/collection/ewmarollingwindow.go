@@ -0,0 +1,67 @@
+package collection
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	_ Stat = (*RollingWindow)(nil)
+	_ Stat = (*EWMARollingWindow)(nil)
+)
+
+// Stat is satisfied by both RollingWindow and EWMARollingWindow, so breaker
+// and loadshed style callers can switch between a bucketed window and a
+// decaying average without changing their call sites.
+type Stat interface {
+	// Add records v.
+	Add(v float64)
+	// Aggregate returns the current Sum/Count estimate.
+	Aggregate() Bucket
+}
+
+// EWMARollingWindow is a Stat that keeps a single exponentially weighted
+// moving average instead of discrete buckets, so there's no bucket to
+// suddenly expire and no resulting cliff in the reported value.
+type EWMARollingWindow struct {
+	lock  sync.Mutex
+	alpha float64
+	sum   float64
+	count int64
+}
+
+// NewEWMARollingWindow returns an EWMARollingWindow that decays with the
+// given halfLife, assuming Add is called roughly every interval. alpha is
+// derived from interval/halfLife so that, like a bucketed window, a faster
+// halfLife forgets old values sooner.
+func NewEWMARollingWindow(interval, halfLife time.Duration) *EWMARollingWindow {
+	return &EWMARollingWindow{
+		alpha: 1 - math.Exp(-math.Ln2*interval.Seconds()/halfLife.Seconds()),
+	}
+}
+
+// Add folds v into the moving average as x = alpha*v + (1-alpha)*x, and bumps
+// the sample count. The count is a plain running total, not decayed: decaying
+// it the same way as sum would asymptotically approach 1 and never reach it,
+// so Aggregate().Count would truncate to 0 forever.
+func (w *EWMARollingWindow) Add(v float64) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.sum = w.alpha*v + (1-w.alpha)*w.sum
+	w.count++
+}
+
+// Aggregate returns the current moving average, together with the total
+// number of Add calls so far, as a Bucket, so callers can treat it the same
+// way as a RollingWindow's Aggregate.
+func (w *EWMARollingWindow) Aggregate() Bucket {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return Bucket{
+		Sum:   w.sum,
+		Count: w.count,
+	}
+}
@@ -0,0 +1,39 @@
+package collection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEWMARollingWindow_CountAccumulates(t *testing.T) {
+	w := NewEWMARollingWindow(time.Millisecond*10, time.Second)
+	for i := 0; i < 1000; i++ {
+		w.Add(1)
+	}
+
+	agg := w.Aggregate()
+	assert.EqualValues(t, 1000, agg.Count)
+	assert.InDelta(t, 1, agg.Sum, 1e-3)
+}
+
+func TestEWMARollingWindow_SatisfiesStat(t *testing.T) {
+	var s Stat = NewEWMARollingWindow(time.Millisecond*10, time.Second)
+	s.Add(5)
+	assert.EqualValues(t, 1, s.Aggregate().Count)
+}
+
+func TestRollingWindow_WeightedAggregateFreshBucketNearFullWeight(t *testing.T) {
+	rw := NewRollingWindow(5, time.Millisecond*100, WithBucketDecay(time.Second))
+	rw.Add(10)
+	// let two intervals elapse with no further Add, so span() > 0 when we
+	// reduce, exercising the idle-gap case where fewer than size buckets are live.
+	time.Sleep(time.Millisecond * 250)
+
+	agg := rw.WeightedAggregate()
+	// the bucket holding this Add is the freshest live bucket, so its weight
+	// should be close to 1, not penalized by the full window size.
+	assert.InDelta(t, 10, agg.Sum, 1)
+	assert.EqualValues(t, 1, agg.Count)
+}
@@ -0,0 +1,123 @@
+package collection
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/timex"
+)
+
+// lockFreeBucket is one slot of a LocklessRollingWindow. All fields are only
+// ever touched through the sync/atomic package; startMs is kept first so it
+// stays 64-bit aligned on 32-bit platforms.
+type lockFreeBucket struct {
+	startMs int64
+	sumBits uint64 // float64 bits of the running sum, see math.Float64bits
+	count   uint64
+}
+
+// LocklessRollingWindow is a RollingWindow variant for the write-heavy path:
+// Add never takes a lock, it CASes straight into the bucket slot for the
+// current time, Sentinel-Go "LeapArray" style. This removes the writer
+// exclusion between Add and Reduce at the cost of only supporting a plain
+// sum/count per bucket.
+type LocklessRollingWindow struct {
+	size     int
+	bucketMs int64
+	buckets  []lockFreeBucket
+}
+
+// unwrittenBucket marks a slot that has never been claimed by Add. It must be
+// a value startMs can never legitimately hold -- 0 doesn't qualify, since
+// nowMs/bucketMs-aligned start times are 0 for anything added within the
+// first bucket interval after process start.
+const unwrittenBucket = -1
+
+// NewLocklessRollingWindow returns a LocklessRollingWindow with size buckets,
+// each covering bucketInterval of time.
+func NewLocklessRollingWindow(size int, bucketInterval time.Duration) *LocklessRollingWindow {
+	if size < 1 {
+		panic("size must be greater than 0")
+	}
+
+	buckets := make([]lockFreeBucket, size)
+	for i := range buckets {
+		buckets[i].startMs = unwrittenBucket
+	}
+
+	return &LocklessRollingWindow{
+		size:     size,
+		bucketMs: bucketInterval.Milliseconds(),
+		buckets:  buckets,
+	}
+}
+
+// Add records v into the bucket for the current time, without blocking any
+// other goroutine calling Add or Reduce.
+func (w *LocklessRollingWindow) Add(v float64) {
+	nowMs := int64(timex.Now() / time.Millisecond)
+	startMs := nowMs - nowMs%w.bucketMs
+	idx := int((nowMs / w.bucketMs) % int64(w.size))
+	b := &w.buckets[idx]
+
+	for {
+		cur := atomic.LoadInt64(&b.startMs)
+		if cur >= startMs {
+			break
+		}
+		// the slot belongs to an earlier window, try to claim it for startMs
+		if atomic.CompareAndSwapInt64(&b.startMs, cur, startMs) {
+			atomic.StoreUint64(&b.sumBits, 0)
+			atomic.StoreUint64(&b.count, 0)
+			break
+		}
+		// lost the race to another writer rotating the same slot, retry
+	}
+
+	addFloat64(&b.sumBits, v)
+	atomic.AddUint64(&b.count, 1)
+}
+
+// Reduce runs fn on every bucket whose start time falls inside the live
+// window, i.e. within (now-size*bucketInterval, now]; stale slots are
+// filtered by timestamp rather than a shared offset.
+func (w *LocklessRollingWindow) Reduce(fn func(b Bucket)) {
+	nowMs := int64(timex.Now() / time.Millisecond)
+	windowStart := nowMs - int64(w.size)*w.bucketMs
+
+	for i := range w.buckets {
+		b := &w.buckets[i]
+		startMs := atomic.LoadInt64(&b.startMs)
+		if startMs == unwrittenBucket || startMs <= windowStart || startMs > nowMs {
+			continue
+		}
+
+		fn(Bucket{
+			Sum:   math.Float64frombits(atomic.LoadUint64(&b.sumBits)),
+			Count: int64(atomic.LoadUint64(&b.count)),
+		})
+	}
+}
+
+// Aggregate merges every live bucket into a single Bucket, see Reduce.
+func (w *LocklessRollingWindow) Aggregate() Bucket {
+	var agg Bucket
+	w.Reduce(func(b Bucket) {
+		agg.Sum += b.Sum
+		agg.Count += b.Count
+	})
+	return agg
+}
+
+// addFloat64 atomically adds delta to the float64 stored as bits in addr,
+// retrying the load-add-CAS until it wins the race.
+func addFloat64(addr *uint64, delta float64) {
+	for {
+		old := atomic.LoadUint64(addr)
+		newVal := math.Float64frombits(old) + delta
+		if atomic.CompareAndSwapUint64(addr, old, math.Float64bits(newVal)) {
+			return
+		}
+	}
+}
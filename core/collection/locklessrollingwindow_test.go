@@ -0,0 +1,60 @@
+package collection
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocklessRollingWindow_AddReduce(t *testing.T) {
+	w := NewLocklessRollingWindow(3, time.Millisecond*50)
+	w.Add(1)
+	w.Add(2)
+	w.Add(3)
+
+	agg := w.Aggregate()
+	assert.Equal(t, float64(6), agg.Sum)
+	assert.EqualValues(t, 3, agg.Count)
+}
+
+func TestLocklessRollingWindow_ExpiredBucketsAreDropped(t *testing.T) {
+	w := NewLocklessRollingWindow(3, time.Millisecond*20)
+	w.Add(1)
+	time.Sleep(time.Millisecond * 80)
+	w.Add(2)
+
+	agg := w.Aggregate()
+	assert.Equal(t, float64(2), agg.Sum)
+	assert.EqualValues(t, 1, agg.Count)
+}
+
+// TestLocklessRollingWindow_ConcurrentAdd exercises many goroutines hammering
+// Add (and a few concurrently calling Reduce) at once -- run with -race to
+// confirm there's no data race on the bucket slots.
+func TestLocklessRollingWindow_ConcurrentAdd(t *testing.T) {
+	w := NewLocklessRollingWindow(5, time.Millisecond*20)
+
+	const goroutines = 50
+	const perGoroutine = 200
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				w.Add(1)
+				if j%10 == 0 {
+					w.Reduce(func(b Bucket) {})
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	agg := w.Aggregate()
+	assert.LessOrEqual(t, agg.Count, int64(goroutines*perGoroutine))
+	assert.GreaterOrEqual(t, agg.Count, int64(0))
+}
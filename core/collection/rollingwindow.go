@@ -1,6 +1,7 @@
 package collection
 
 import (
+	"math"
 	"sync"
 	"time"
 
@@ -8,40 +9,56 @@ import (
 )
 
 type (
-	// RollingWindowOption let callers customize the RollingWindow.
-	RollingWindowOption func(rollingWindow *RollingWindow)
+	// BucketAggregator aggregates float64 values recorded with Add into a bucket of
+	// type B, so a RollingWindowG can be reused for counters, gauges, quantile
+	// sketches, histograms, etc. Reset must clear every value recorded into the
+	// bucket (it may keep allocated storage, such as a histogram's Bounds and
+	// Counts), and Merge must fold src into dst as if every value recorded into src had
+	// instead been recorded into dst.
+	BucketAggregator[B any] interface {
+		// Add records v into b.
+		Add(b *B, v float64)
+		// Reset clears b so it can be reused for a new time slot.
+		Reset(b *B)
+		// Merge folds src into dst.
+		Merge(dst *B, src B)
+	}
+
+	// RollingWindowOptionG let callers customize the RollingWindowG.
+	RollingWindowOptionG[B any] func(rollingWindow *RollingWindowG[B])
 
-	// RollingWindow defines a rolling window to calculate the events in buckets with time interval.
-	// 这个滑动窗口实现很不错，学习如何处理连续信号！
-	RollingWindow struct {
+	// RollingWindowG defines a rolling window that aggregates events into buckets
+	// of type B over a series of time intervals, using the given BucketAggregator
+	// to decide how values are combined within and across buckets.
+	RollingWindowG[B any] struct {
 		lock          sync.RWMutex
-		// 窗口分片数量，即窗口内的采样数 
 		size          int
-		// 实际窗口，数据容器
-		win           *window
-		// 采样间隔
+		win           *windowG[B]
+		aggregator    BucketAggregator[B]
 		interval      time.Duration
-		// 游标位置
 		offset        int
 		ignoreCurrent bool
-		// 最后一个时间片的起始点（可以理解为采样点，离散信号）
 		lastTime      time.Duration // start time of the last bucket
+		decayHalfLife time.Duration // set by WithBucketDecay, zero disables decay
+		onExpire      func(b *B)    // set by TieredRollingWindow to roll expired buckets up a tier
 	}
-	// 40个采样点，间隔 250ms，则滑动窗口总大小 10s（10s的连续信号，分为40个区间采样 —— 由40个离散信号来统计这个连续区间）
 )
 
-// NewRollingWindow returns a RollingWindow that with size buckets and time interval,
-// use opts to customize the RollingWindow.
-func NewRollingWindow(size int, interval time.Duration, opts ...RollingWindowOption) *RollingWindow {
+// NewRollingWindowG returns a RollingWindowG that has size buckets, spaced interval
+// apart, whose values are combined with aggregator. Use opts to customize the
+// RollingWindowG.
+func NewRollingWindowG[B any](size int, interval time.Duration, aggregator BucketAggregator[B],
+	opts ...RollingWindowOptionG[B]) *RollingWindowG[B] {
 	if size < 1 {
 		panic("size must be greater than 0")
 	}
 
-	w := &RollingWindow{
-		size:     size,
-		win:      newWindow(size),
-		interval: interval,
-		lastTime: timex.Now(),
+	w := &RollingWindowG[B]{
+		size:       size,
+		win:        newWindowG[B](size),
+		aggregator: aggregator,
+		interval:   interval,
+		lastTime:   timex.Now(),
 	}
 	for _, opt := range opts {
 		opt(w)
@@ -49,62 +66,108 @@ func NewRollingWindow(size int, interval time.Duration, opts ...RollingWindowOpt
 	return w
 }
 
-// Add adds value to current bucket.
-func (rw *RollingWindow) Add(v float64) {
+// Add adds v to the current bucket.
+func (rw *RollingWindowG[B]) Add(v float64) {
 	rw.lock.Lock()
 	defer rw.lock.Unlock()
-	// 调整游标
 	rw.updateOffset()
-	// 统计记录
-	rw.win.add(rw.offset, v)
+	rw.win.add(rw.aggregator, rw.offset, v)
 }
 
-// Reduce runs fn on all buckets, ignore current bucket if ignoreCurrent was set.
-// 在滑动窗口有效区间上执行统计的函数
-func (rw *RollingWindow) Reduce(fn func(b *Bucket)) {
+// Reduce runs fn on every live bucket, ignoring the current bucket if
+// ignoreCurrent was set.
+func (rw *RollingWindowG[B]) Reduce(fn func(b *B)) {
 	rw.lock.RLock()
 	defer rw.lock.RUnlock()
 
-	// diff 为窗口内，有效采样数量
-	var diff int 
-	// 当前时间与上一个采样点的跨度
+	var diff int
 	span := rw.span()
 	// ignore current bucket, because of partial data
 	if span == 0 && rw.ignoreCurrent {
-		// span 为零，依然在当前采样时间段内
-		// ignoreCurrent 可能当前时间段的统计会有偏差，所以在整个窗口上，排除当前小窗口，即为需要统计的范围
 		diff = rw.size - 1
 	} else {
-		// 当 span 不为 0， 说明有一些小窗口数据无效、已过期
-		// offset 指向窗口完整且有效，当前时间窗口为可覆盖的历史窗口，无效
 		diff = rw.size - span
 	}
 	if diff > 0 {
-		//rw.offset + span 为过期窗口，无效数据，从 +1 开始（当前时间点也是无效数据）
 		offset := (rw.offset + span + 1) % rw.size
-		// 从 offset 开始，遍历 diff 个，注意理解这里，如何用离散信号来处理连续信号
-		// 这里遍历的是落在整个时间窗口内的有效时间段
-		// 并不是绝对意义的 (current-W, current), 处理连续信号要记录每一个数据以及时间点，是很难处理的
-		rw.win.reduce(offset, diff, fn) // 从有效采样点开始，遍历 diff 个
+		rw.win.reduce(offset, diff, fn)
+	}
+}
+
+// Aggregate merges every live bucket visited by Reduce into a single B, using the
+// window's aggregator, so callers don't have to re-implement the bucket
+// traversal just to collapse the window down to one value.
+func (rw *RollingWindowG[B]) Aggregate() B {
+	var agg B
+	rw.Reduce(func(b *B) {
+		rw.aggregator.Merge(&agg, *b)
+	})
+	return agg
+}
+
+// reduceWeighted behaves like Reduce, but additionally passes fn the bucket's
+// exponential decay weight relative to the most recent bucket, so the oldest
+// buckets fade out smoothly instead of dropping off the window all at once.
+// The weight is 1 when WithBucketDecay wasn't used.
+func (rw *RollingWindowG[B]) reduceWeighted(fn func(b *B, weight float64)) {
+	rw.lock.RLock()
+	defer rw.lock.RUnlock()
+
+	var diff int
+	span := rw.span()
+	if span == 0 && rw.ignoreCurrent {
+		diff = rw.size - 1
+	} else {
+		diff = rw.size - span
+	}
+	if diff <= 0 {
+		return
+	}
+
+	offset := (rw.offset + span + 1) % rw.size
+	for i := 0; i < diff; i++ {
+		weight := 1.0
+		if rw.decayHalfLife > 0 {
+			// age is relative to the diff buckets actually being visited, not
+			// the full window size, so the freshest live bucket (i == diff-1)
+			// always gets age ~0 regardless of how stale the window is.
+			age := time.Duration(diff-1-i) * rw.interval
+			weight = math.Exp(-math.Ln2 * age.Seconds() / rw.decayHalfLife.Seconds())
+		}
+		fn(rw.win.buckets[(offset+i)%rw.size], weight)
 	}
 }
 
-// 当前时间与上一个采样点的跨度
-func (rw *RollingWindow) span() int {
-	// 看这里，这里有取整操作
-	// 时间是连续的，属于模拟信号，有无限取值，这里细分为小时间段，来离散化
-	// 将当前时间落在了某个小片段，将模拟信号变为不连续的、离散的数字信号！！！！！
-	// 微积分思想在处理连续信号时，非常重要！！
+// WeightedAggregate is like Aggregate, but scales each live bucket's Sum and
+// Count by its WithBucketDecay weight before merging, so a breaker built on
+// the built-in count/sum RollingWindow doesn't see a cliff when the oldest
+// bucket rolls off. Bucket types other than the built-in Bucket fall back to
+// a plain, unweighted Aggregate.
+func (rw *RollingWindowG[B]) WeightedAggregate() B {
+	var agg B
+	aggBucket, ok := any(&agg).(*Bucket)
+	if !ok {
+		return rw.Aggregate()
+	}
+
+	rw.reduceWeighted(func(b *B, weight float64) {
+		bucket := any(b).(*Bucket)
+		aggBucket.Sum += bucket.Sum * weight
+		aggBucket.Count += int64(float64(bucket.Count) * weight)
+	})
+	return agg
+}
+
+func (rw *RollingWindowG[B]) span() int {
 	offset := int(timex.Since(rw.lastTime) / rw.interval)
 	if 0 <= offset && offset < rw.size {
 		return offset
 	}
-	// 跨度以及超过完整的窗口，记为窗口大小即可	
+
 	return rw.size
 }
 
-// 这个函数实现了时间窗口的滑动
-func (rw *RollingWindow) updateOffset() {
+func (rw *RollingWindowG[B]) updateOffset() {
 	span := rw.span()
 	if span <= 0 {
 		return
@@ -113,67 +176,149 @@ func (rw *RollingWindow) updateOffset() {
 	offset := rw.offset
 	// reset expired buckets
 	for i := 0; i < span; i++ {
-		rw.win.resetBucket((offset + i + 1) % rw.size)
+		idx := (offset + i + 1) % rw.size
+		if rw.onExpire != nil {
+			expired := *rw.win.buckets[idx]
+			rw.win.resetBucket(rw.aggregator, idx)
+			rw.onExpire(&expired)
+		} else {
+			rw.win.resetBucket(rw.aggregator, idx)
+		}
 	}
 
 	rw.offset = (offset + span) % rw.size
 	now := timex.Now()
 	// align to interval time boundary
-	// 这里很重要，lastTime 记录的并不是事件发生时间点，而是当前时间片的起点值，可以理解为连续信号的采样点
 	rw.lastTime = now - (now-rw.lastTime)%rw.interval
 }
 
+// mergeBucket folds src directly into the current bucket, without treating it
+// as a single raw value added via the aggregator's Add. Used by
+// TieredRollingWindow to roll an expired fine-grained bucket up into the
+// current bucket of the next coarser tier.
+func (rw *RollingWindowG[B]) mergeBucket(src B) {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+	rw.updateOffset()
+	rw.aggregator.Merge(rw.win.buckets[rw.offset%rw.size], src)
+}
+
+// reduceLast behaves like Reduce, but visits at most the n most recent live
+// buckets instead of the whole window, so callers that only need "the last d
+// of this tier" don't have to walk buckets they don't need.
+func (rw *RollingWindowG[B]) reduceLast(n int, fn func(b *B)) {
+	rw.lock.RLock()
+	defer rw.lock.RUnlock()
+
+	var diff int
+	span := rw.span()
+	if span == 0 && rw.ignoreCurrent {
+		diff = rw.size - 1
+	} else {
+		diff = rw.size - span
+	}
+	if diff <= 0 {
+		return
+	}
+
+	start := (rw.offset + span + 1) % rw.size
+	if n < diff {
+		start = (start + diff - n) % rw.size
+		diff = n
+	}
+	rw.win.reduce(start, diff, fn)
+}
+
+type (
+	// RollingWindowOption let callers customize the RollingWindow.
+	RollingWindowOption = RollingWindowOptionG[Bucket]
+
+	// RollingWindow defines a rolling window to calculate the events in buckets
+	// with time interval. It's a RollingWindowG specialized with the built-in
+	// count/sum Bucket, kept around so existing callers don't need generics.
+	RollingWindow = RollingWindowG[Bucket]
+)
+
+// NewRollingWindow returns a RollingWindow that with size buckets and time interval,
+// use opts to customize the RollingWindow.
+func NewRollingWindow(size int, interval time.Duration, opts ...RollingWindowOption) *RollingWindow {
+	return NewRollingWindowG[Bucket](size, interval, countSumAggregator{}, opts...)
+}
+
 // Bucket defines the bucket that holds sum and num of additions.
 type Bucket struct {
 	Sum   float64
 	Count int64
 }
 
-func (b *Bucket) add(v float64) {
+// countSumAggregator is the BucketAggregator backing the classic RollingWindow,
+// it simply accumulates a sum and a count per bucket.
+type countSumAggregator struct{}
+
+func (countSumAggregator) Add(b *Bucket, v float64) {
 	b.Sum += v
 	b.Count++
 }
 
-func (b *Bucket) reset() {
+func (countSumAggregator) Reset(b *Bucket) {
 	b.Sum = 0
 	b.Count = 0
 }
 
-// 窗口，即数据容器
-type window struct {
-	buckets []*Bucket
+func (countSumAggregator) Merge(dst *Bucket, src Bucket) {
+	dst.Sum += src.Sum
+	dst.Count += src.Count
+}
+
+// window holds the buckets of a RollingWindowG.
+type windowG[B any] struct {
+	buckets []*B
 	size    int
 }
 
-func newWindow(size int) *window {
-	buckets := make([]*Bucket, size)
-	// Bucket 初始化
+func newWindowG[B any](size int) *windowG[B] {
+	buckets := make([]*B, size)
 	for i := 0; i < size; i++ {
-		buckets[i] = new(Bucket)
+		buckets[i] = new(B)
 	}
-	return &window{
+	return &windowG[B]{
 		buckets: buckets,
 		size:    size,
 	}
 }
 
-func (w *window) add(offset int, v float64) {
-	w.buckets[offset%w.size].add(v)
+func (w *windowG[B]) add(aggregator BucketAggregator[B], offset int, v float64) {
+	aggregator.Add(w.buckets[offset%w.size], v)
 }
 
-func (w *window) reduce(start, count int, fn func(b *Bucket)) {
+func (w *windowG[B]) reduce(start, count int, fn func(b *B)) {
 	for i := 0; i < count; i++ {
 		fn(w.buckets[(start+i)%w.size])
 	}
 }
 
-func (w *window) resetBucket(offset int) {
-	w.buckets[offset%w.size].reset()
+func (w *windowG[B]) resetBucket(aggregator BucketAggregator[B], offset int) {
+	aggregator.Reset(w.buckets[offset%w.size])
+}
+
+// IgnoreCurrentBucketG lets the Reduce call ignore current bucket.
+func IgnoreCurrentBucketG[B any]() RollingWindowOptionG[B] {
+	return func(w *RollingWindowG[B]) {
+		w.ignoreCurrent = true
+	}
 }
 
 // IgnoreCurrentBucket lets the Reduce call ignore current bucket.
 func IgnoreCurrentBucket() RollingWindowOption {
-	return func(w *RollingWindow) {
-		w.ignoreCurrent = true
+	return IgnoreCurrentBucketG[Bucket]()
+}
+
+// WithBucketDecay makes WeightedAggregate (and reduceWeighted) scale each live
+// bucket by exp(-ln2 * age/halfLife), where age is how far the bucket trails
+// the most recent one. This smooths out the cliff a Google-SRE-style breaker
+// would otherwise see the instant the oldest bucket expires.
+func WithBucketDecay(halfLife time.Duration) RollingWindowOption {
+	return func(rw *RollingWindow) {
+		rw.decayHalfLife = halfLife
 	}
 }
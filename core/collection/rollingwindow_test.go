@@ -0,0 +1,95 @@
+package collection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollingWindow_AddReduce(t *testing.T) {
+	rw := NewRollingWindow(3, time.Millisecond*50)
+	rw.Add(1)
+	rw.Add(2)
+	rw.Add(3)
+
+	var sum float64
+	var count int64
+	rw.Reduce(func(b *Bucket) {
+		sum += b.Sum
+		count += b.Count
+	})
+
+	assert.Equal(t, float64(6), sum)
+	assert.EqualValues(t, 3, count)
+}
+
+func TestRollingWindow_IgnoreCurrentBucket(t *testing.T) {
+	rw := NewRollingWindow(3, time.Millisecond*50, IgnoreCurrentBucket())
+	rw.Add(1)
+	rw.Add(2)
+
+	agg := rw.Aggregate()
+	assert.EqualValues(t, 0, agg.Count)
+}
+
+func TestRollingWindow_ExpiredBucketsAreDropped(t *testing.T) {
+	rw := NewRollingWindow(3, time.Millisecond*20)
+	rw.Add(1)
+	time.Sleep(time.Millisecond * 70)
+	rw.Add(2)
+
+	agg := rw.Aggregate()
+	assert.Equal(t, float64(2), agg.Sum)
+	assert.EqualValues(t, 1, agg.Count)
+}
+
+func TestMinMaxAggregator(t *testing.T) {
+	rw := NewRollingWindowG[MinMaxBucket](5, time.Millisecond*50, MinMaxAggregator())
+	rw.Add(3)
+	rw.Add(-1)
+	rw.Add(7)
+
+	agg := rw.Aggregate()
+	assert.Equal(t, float64(-1), agg.Min)
+	assert.Equal(t, float64(7), agg.Max)
+	assert.EqualValues(t, 3, agg.Count)
+}
+
+func TestQuantileAggregator(t *testing.T) {
+	rw := NewRollingWindowG[QuantileBucket](5, time.Millisecond*50, QuantileAggregator())
+	for i := 1; i <= 100; i++ {
+		rw.Add(float64(i))
+	}
+
+	agg := rw.Aggregate()
+	p50 := agg.Quantile(0.5)
+	assert.InDelta(t, 50, p50, 15)
+}
+
+func TestHistogramAggregator(t *testing.T) {
+	rw := NewRollingWindowG[HistogramBucket](5, time.Millisecond*50,
+		HistogramAggregator([]float64{1, 5, 10}))
+	rw.Add(0.5)
+	rw.Add(3)
+	rw.Add(20)
+
+	agg := rw.Aggregate()
+	assert.Equal(t, []int64{1, 1, 0, 1}, agg.Counts)
+	assert.EqualValues(t, 3, agg.Count)
+}
+
+func TestLatencyAggregator(t *testing.T) {
+	rw := NewRollingWindowG[LatencyBucket](5, time.Millisecond*50,
+		LatencyAggregator(1, 1000, 2))
+	rw.Add(5)
+	rw.Add(500)
+
+	agg := rw.Aggregate()
+	assert.EqualValues(t, 2, agg.Count)
+	var total int64
+	for _, c := range agg.Counts {
+		total += c
+	}
+	assert.EqualValues(t, 2, total)
+}
@@ -0,0 +1,174 @@
+package collection
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/timex"
+)
+
+// ErrSnapshotMismatch is returned by RollingWindowSnapshot.Merge when the two
+// snapshots don't come from RollingWindows configured with the same interval
+// and bucket count, and therefore can't be aligned bucket for bucket.
+var ErrSnapshotMismatch = errors.New("collection: snapshots have different interval or size")
+
+// ErrSnapshotUnsupported is returned by Snapshot when called on a
+// RollingWindowG instantiated with anything other than the built-in
+// count/sum Bucket.
+var ErrSnapshotUnsupported = errors.New(
+	"collection: Snapshot only supports the built-in count/sum RollingWindow")
+
+// RollingWindowSnapshot is a point-in-time, lock-free copy of a RollingWindow's
+// buckets, suitable for sending to another process (e.g. over Redis pub/sub or
+// gRPC) so a "global" breaker can aggregate per-pod windows without reaching
+// into the RollingWindow's private fields.
+//
+// CapturedAt and Age are deliberately wall-clock based rather than derived
+// from timex.Now(): timex.Now() measures elapsed time since each process's
+// own start, which has no meaning across processes, so two pods with
+// different uptimes would otherwise align on an arbitrary, meaningless
+// offset when merged.
+type RollingWindowSnapshot struct {
+	Buckets []Bucket
+	// Interval is the bucket width, required to be equal between snapshots
+	// being merged.
+	Interval time.Duration
+	// Offset is the index of the current bucket within Buckets.
+	Offset int
+	// CapturedAt is the wall-clock time Snapshot was called.
+	CapturedAt time.Time
+	// Age is how long before CapturedAt the current bucket started.
+	Age time.Duration
+}
+
+// Snapshot returns a copy of rw's live buckets together with enough state
+// (Interval, Offset, CapturedAt, Age) to later Merge it with a snapshot taken
+// from another process. Snapshot only supports the built-in count/sum
+// RollingWindow; calling it on any other BucketAggregator instantiation
+// returns ErrSnapshotUnsupported.
+func (rw *RollingWindowG[B]) Snapshot() (RollingWindowSnapshot, error) {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+
+	// buckets that fell out of the live window aren't zeroed until the next
+	// Add/mergeBucket touches them, so without this they'd still read as
+	// current data here even though Reduce would have excluded them.
+	rw.updateOffset()
+
+	buckets := make([]Bucket, rw.size)
+	for i, b := range rw.win.buckets {
+		bucket, ok := any(*b).(Bucket)
+		if !ok {
+			return RollingWindowSnapshot{}, ErrSnapshotUnsupported
+		}
+		buckets[i] = bucket
+	}
+
+	return RollingWindowSnapshot{
+		Buckets:    buckets,
+		Interval:   rw.interval,
+		Offset:     rw.offset,
+		CapturedAt: time.Now(),
+		Age:        timex.Since(rw.lastTime),
+	}, nil
+}
+
+// Merge aligns other to s's time base and returns a new snapshot whose
+// buckets are the bucketwise Sum/Count of both, so e.g. per-pod windows can
+// be combined into a cluster-wide one. Buckets of other that fall outside the
+// window s covers are dropped. s and other must come from RollingWindows with
+// the same Interval and number of buckets, and are aligned using their
+// wall-clock CapturedAt/Age, not any process-local clock, so this is safe to
+// call across processes (assuming their wall clocks are reasonably in sync).
+func (s RollingWindowSnapshot) Merge(other RollingWindowSnapshot) (RollingWindowSnapshot, error) {
+	size := len(s.Buckets)
+	if s.Interval != other.Interval || size != len(other.Buckets) {
+		return RollingWindowSnapshot{}, ErrSnapshotMismatch
+	}
+
+	// real-world start time of each snapshot's current (offset) bucket
+	sBucketStart := s.CapturedAt.Add(-s.Age)
+	otherBucketStart := other.CapturedAt.Add(-other.Age)
+	// how many buckets other's current bucket trails s's by, e.g. s's bucket
+	// starting at t=10 and other's at t=7 (Interval=1) gives shift=3: s's
+	// age-3 bucket (t=7) is other's age-0 bucket, i.e. otherAge = age - shift
+	shift := int(sBucketStart.Sub(otherBucketStart) / s.Interval)
+
+	merged := RollingWindowSnapshot{
+		Buckets:    make([]Bucket, size),
+		Interval:   s.Interval,
+		Offset:     s.Offset,
+		CapturedAt: s.CapturedAt,
+		Age:        s.Age,
+	}
+	for age := 0; age < size; age++ {
+		idx := ((s.Offset-age)%size + size) % size
+		merged.Buckets[idx] = s.Buckets[idx]
+
+		otherAge := age - shift
+		if otherAge < 0 || otherAge >= size {
+			// falls outside the window the two snapshots have in common
+			continue
+		}
+		otherIdx := ((other.Offset-otherAge)%size + size) % size
+		merged.Buckets[idx].Sum += other.Buckets[otherIdx].Sum
+		merged.Buckets[idx].Count += other.Buckets[otherIdx].Count
+	}
+	return merged, nil
+}
+
+// snapshotHeaderLen is the byte size of everything in MarshalBinary's output
+// before the bucket list: Interval, CapturedAt (as UnixNano) and Age (8 bytes
+// each), then Offset and the bucket count (4 bytes each).
+const snapshotHeaderLen = 8 + 8 + 8 + 4 + 4
+
+// snapshotBucketLen is the encoded byte size of a single Bucket: Sum and
+// Count, 8 bytes each.
+const snapshotBucketLen = 8 + 8
+
+// MarshalBinary encodes s so it can be sent over the wire, e.g. gossiped
+// between replicas for a cluster-wide breaker decision.
+func (s RollingWindowSnapshot) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, snapshotHeaderLen+len(s.Buckets)*snapshotBucketLen)
+	binary.BigEndian.PutUint64(buf[0:], uint64(s.Interval))
+	binary.BigEndian.PutUint64(buf[8:], uint64(s.CapturedAt.UnixNano()))
+	binary.BigEndian.PutUint64(buf[16:], uint64(s.Age))
+	binary.BigEndian.PutUint32(buf[24:], uint32(s.Offset))
+	binary.BigEndian.PutUint32(buf[28:], uint32(len(s.Buckets)))
+
+	for i, b := range s.Buckets {
+		off := snapshotHeaderLen + i*snapshotBucketLen
+		binary.BigEndian.PutUint64(buf[off:], math.Float64bits(b.Sum))
+		binary.BigEndian.PutUint64(buf[off+8:], uint64(b.Count))
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a snapshot encoded by MarshalBinary.
+func (s *RollingWindowSnapshot) UnmarshalBinary(data []byte) error {
+	if len(data) < snapshotHeaderLen {
+		return errors.New("collection: truncated RollingWindowSnapshot header")
+	}
+
+	s.Interval = time.Duration(binary.BigEndian.Uint64(data[0:]))
+	s.CapturedAt = time.Unix(0, int64(binary.BigEndian.Uint64(data[8:])))
+	s.Age = time.Duration(binary.BigEndian.Uint64(data[16:]))
+	s.Offset = int(binary.BigEndian.Uint32(data[24:]))
+	n := int(binary.BigEndian.Uint32(data[28:]))
+
+	data = data[snapshotHeaderLen:]
+	if len(data) < n*snapshotBucketLen {
+		return errors.New("collection: truncated RollingWindowSnapshot buckets")
+	}
+
+	buckets := make([]Bucket, n)
+	for i := range buckets {
+		off := i * snapshotBucketLen
+		buckets[i].Sum = math.Float64frombits(binary.BigEndian.Uint64(data[off:]))
+		buckets[i].Count = int64(binary.BigEndian.Uint64(data[off+8:]))
+	}
+	s.Buckets = buckets
+	return nil
+}
@@ -0,0 +1,186 @@
+package collection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollingWindowSnapshot_SnapshotRejectsNonBucket(t *testing.T) {
+	rw := NewRollingWindowG[MinMaxBucket](5, time.Millisecond*10, MinMaxAggregator())
+	_, err := rw.Snapshot()
+	assert.ErrorIs(t, err, ErrSnapshotUnsupported)
+}
+
+func TestRollingWindowSnapshot_MarshalRoundTrip(t *testing.T) {
+	rw := NewRollingWindow(5, time.Millisecond*10)
+	rw.Add(1)
+	rw.Add(2)
+	snap, err := rw.Snapshot()
+	assert.NoError(t, err)
+
+	data, err := snap.MarshalBinary()
+	assert.NoError(t, err)
+
+	var out RollingWindowSnapshot
+	assert.NoError(t, out.UnmarshalBinary(data))
+	assert.Equal(t, snap.Buckets, out.Buckets)
+	assert.Equal(t, snap.Interval, out.Interval)
+	assert.Equal(t, snap.Offset, out.Offset)
+	assert.Equal(t, snap.Age, out.Age)
+	assert.WithinDuration(t, snap.CapturedAt, out.CapturedAt, 0)
+}
+
+// TestRollingWindowSnapshot_SnapshotDropsStaleBuckets covers the routine case
+// where Snapshot is called after a bucket has aged out of the live window but
+// before the next Add/mergeBucket lazily resets it (buckets are only zeroed
+// on next write, see updateOffset). Snapshot must reproduce the same
+// live/expired split Reduce applies, not copy rw.win.buckets verbatim.
+func TestRollingWindowSnapshot_SnapshotDropsStaleBuckets(t *testing.T) {
+	rw := NewRollingWindow(2, time.Millisecond*10)
+	rw.Add(5)
+
+	// let the whole window elapse without another Add, so the bucket holding
+	// 5 is now stale but hasn't been reset by a write.
+	time.Sleep(time.Millisecond * 25)
+
+	snap, err := rw.Snapshot()
+	assert.NoError(t, err)
+
+	var totalCount int64
+	for _, b := range snap.Buckets {
+		totalCount += b.Count
+	}
+	assert.EqualValues(t, 0, totalCount)
+}
+
+// TestRollingWindowSnapshot_MergeSurvivesUptimeSkew reproduces two independent
+// RollingWindows -- standing in for two pods with different process uptimes,
+// i.e. unrelated timex.Now() bases -- each recording one event at the same
+// real instant, and checks that Merge still counts both instead of silently
+// dropping one. Before this fix, Merge aligned on the process-relative
+// LastTime instead of wall-clock CapturedAt/Age, so any uptime difference
+// between the two processes produced a bogus shift.
+func TestRollingWindowSnapshot_MergeSurvivesUptimeSkew(t *testing.T) {
+	rwA := NewRollingWindow(5, time.Millisecond*100)
+	// rwB is constructed well after rwA, standing in for a process that's
+	// been running for a different amount of time; its internal lastTime
+	// base has nothing to do with rwA's. Merge must not care, since it
+	// aligns on wall-clock CapturedAt/Age rather than on lastTime.
+	time.Sleep(time.Millisecond * 30)
+	rwB := NewRollingWindow(5, time.Millisecond*100)
+
+	rwA.Add(10)
+	rwB.Add(10)
+
+	snapA, err := rwA.Snapshot()
+	assert.NoError(t, err)
+	snapB, err := rwB.Snapshot()
+	assert.NoError(t, err)
+
+	merged, err := snapA.Merge(snapB)
+	assert.NoError(t, err)
+
+	var total int64
+	for _, b := range merged.Buckets {
+		total += b.Count
+	}
+	assert.EqualValues(t, 2, total)
+}
+
+// TestRollingWindowSnapshot_MergeAlignsInTheRightDirection pins down the exact
+// scenario from review: s's current bucket starts at t=10, other's at t=7
+// (Interval=1), so shift=3 and s's age-3 bucket (t=7) must be paired with
+// other's age-0 (current) bucket, i.e. otherAge = age - shift. The previous
+// otherAge = age + shift pairs s's newest bucket with data 2*shift buckets
+// further in the past instead, silently combining the wrong time ranges.
+func TestRollingWindowSnapshot_MergeAlignsInTheRightDirection(t *testing.T) {
+	const size = 5
+	const interval = time.Millisecond
+
+	now := time.Now()
+	s := RollingWindowSnapshot{
+		Buckets:    make([]Bucket, size),
+		Interval:   interval,
+		Offset:     0,
+		CapturedAt: now,
+		Age:        0, // s's current bucket starts at "t=10" i.e. now
+	}
+	s.Buckets[0] = Bucket{Sum: 1, Count: 1}
+
+	other := RollingWindowSnapshot{
+		Buckets:    make([]Bucket, size),
+		Interval:   interval,
+		Offset:     0,
+		CapturedAt: now,
+		Age:        3 * interval, // other's current bucket starts at "t=7"
+	}
+	other.Buckets[0] = Bucket{Sum: 100, Count: 100}
+
+	merged, err := s.Merge(other)
+	assert.NoError(t, err)
+
+	// s's age-3 bucket is index (0-3+5)%5 == 2, and must have picked up
+	// other's current (age-0) bucket.
+	assert.Equal(t, Bucket{Sum: 100, Count: 100}, merged.Buckets[2])
+	// s's own current bucket is untouched by other's data.
+	assert.Equal(t, Bucket{Sum: 1, Count: 1}, merged.Buckets[0])
+}
+
+// TestRollingWindowSnapshot_MergeAcrossMultipleIntervals exercises a skew of
+// several whole bucket intervals -- large enough that the sub-interval
+// truncation masking the bug in TestRollingWindowSnapshot_MergeSurvivesUptimeSkew
+// can't hide a sign error -- using real Snapshot calls, and checks the data
+// lands in the correct bucket instead of just checking totals.
+func TestRollingWindowSnapshot_MergeAcrossMultipleIntervals(t *testing.T) {
+	const interval = time.Millisecond * 20
+
+	rwA := NewRollingWindow(5, interval)
+	rwA.Add(10)
+	snapA, err := rwA.Snapshot()
+	assert.NoError(t, err)
+
+	rwB := NewRollingWindow(5, interval)
+	rwB.Add(20)
+	snapB, err := rwB.Snapshot()
+	assert.NoError(t, err)
+
+	// pin both snapshots to the same real instant, both with their current
+	// bucket freshly started, except other's trails s's by exactly 3 whole
+	// intervals, e.g. a replica that's been up noticeably longer. Overriding
+	// Age outright (rather than just adding to the naturally-captured value)
+	// keeps the 3-bucket skew exact regardless of scheduler jitter between
+	// the Add and Snapshot calls above.
+	snapA.Age = 0
+	snapB.CapturedAt = snapA.CapturedAt
+	snapB.Age = 3 * interval
+
+	merged, err := snapA.Merge(snapB)
+	assert.NoError(t, err)
+
+	// s's own current bucket (offset 0) is untouched by other's data.
+	assert.Equal(t, Bucket{Sum: 10, Count: 1}, merged.Buckets[0])
+	// s's age-3 bucket, index (0-3+5)%5 == 2, must have picked up other's
+	// current bucket.
+	assert.Equal(t, Bucket{Sum: 20, Count: 1}, merged.Buckets[2])
+
+	var totalCount int64
+	for _, b := range merged.Buckets {
+		totalCount += b.Count
+	}
+	assert.EqualValues(t, 2, totalCount)
+}
+
+func TestRollingWindowSnapshot_MergeRejectsMismatchedConfig(t *testing.T) {
+	rwA := NewRollingWindow(5, time.Millisecond*100)
+	rwB := NewRollingWindow(4, time.Millisecond*100)
+
+	snapA, err := rwA.Snapshot()
+	assert.NoError(t, err)
+	snapB, err := rwB.Snapshot()
+	assert.NoError(t, err)
+
+	_, err = snapA.Merge(snapB)
+	assert.ErrorIs(t, err, ErrSnapshotMismatch)
+}
@@ -0,0 +1,87 @@
+package collection
+
+import "time"
+
+// Tier describes one resolution level of a TieredRollingWindow: Size buckets
+// of Interval each, e.g. {Interval: 50 * time.Millisecond, Size: 20} for a 1s
+// fine-grained tier used by breakers, or {Interval: 5 * time.Second, Size: 60}
+// for a 5min coarse tier used by adaptive shedding.
+type Tier struct {
+	Interval time.Duration
+	Size     int
+}
+
+// TieredRollingWindow keeps several RollingWindows at progressively coarser
+// resolutions instead of one window fine and long enough to answer both
+// "last 1s" and "last 5min" queries. Only the finest tier receives Add calls;
+// as its buckets expire they're rolled up into the sum/count of the next
+// tier, the same way a TSDB downsamples old samples.
+type TieredRollingWindow struct {
+	tiers []*RollingWindow
+}
+
+// NewTieredRollingWindow returns a TieredRollingWindow with one RollingWindow
+// per tier, ordered from finest to coarsest resolution. Every tier but the
+// last rolls its expired buckets up into the next one as they age out.
+func NewTieredRollingWindow(tiers ...Tier) *TieredRollingWindow {
+	if len(tiers) == 0 {
+		panic("at least one tier is required")
+	}
+
+	t := &TieredRollingWindow{
+		tiers: make([]*RollingWindow, len(tiers)),
+	}
+	for i, spec := range tiers {
+		t.tiers[i] = NewRollingWindow(spec.Size, spec.Interval)
+	}
+	for i := 0; i < len(t.tiers)-1; i++ {
+		next := t.tiers[i+1]
+		t.tiers[i].onExpire = func(b *Bucket) {
+			next.mergeBucket(*b)
+		}
+	}
+	return t
+}
+
+// Add records v into the finest tier; coarser tiers are populated lazily as
+// that tier's buckets expire, see NewTieredRollingWindow.
+func (t *TieredRollingWindow) Add(v float64) {
+	t.tiers[0].Add(v)
+}
+
+// ReduceSince runs fn over the last d of data. Rollup into a coarser tier
+// only happens as a finer tier's buckets expire, so the coarser tier alone
+// would miss whatever hasn't rolled up yet; ReduceSince instead takes every
+// finer tier's live buckets in full (each one's total span is, by
+// construction, shorter than d, or it would have been picked itself) plus
+// just enough of the chosen tier's buckets to cover what's left of d.
+func (t *TieredRollingWindow) ReduceSince(d time.Duration, fn func(b *Bucket)) {
+	sel := len(t.tiers) - 1
+	var finerSpan time.Duration
+	for i, rw := range t.tiers {
+		span := time.Duration(rw.size) * rw.interval
+		if span >= d {
+			sel = i
+			break
+		}
+		finerSpan += span
+	}
+
+	for i := 0; i < sel; i++ {
+		t.tiers[i].Reduce(fn)
+	}
+
+	tier := t.tiers[sel]
+	remaining := d - finerSpan
+	n := int(remaining / tier.interval)
+	if remaining%tier.interval != 0 {
+		n++
+	}
+	if n < 1 {
+		n = 1
+	}
+	if n > tier.size {
+		n = tier.size
+	}
+	tier.reduceLast(n, fn)
+}
@@ -0,0 +1,63 @@
+package collection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTieredRollingWindow_AddGoesToFinestTier(t *testing.T) {
+	tw := NewTieredRollingWindow(
+		Tier{Interval: time.Millisecond * 10, Size: 3},
+		Tier{Interval: time.Millisecond * 100, Size: 3},
+	)
+	tw.Add(5)
+
+	var fineCount int64
+	tw.ReduceSince(time.Millisecond*30, func(b *Bucket) {
+		fineCount += b.Count
+	})
+	assert.EqualValues(t, 1, fineCount)
+}
+
+func TestTieredRollingWindow_RollsUpExpiredBuckets(t *testing.T) {
+	tw := NewTieredRollingWindow(
+		Tier{Interval: time.Millisecond * 10, Size: 2},
+		Tier{Interval: time.Millisecond * 100, Size: 3},
+	)
+	tw.Add(1)
+	tw.Add(2)
+
+	// let the fine tier (2 * 10ms = 20ms span) fully roll over, so its
+	// buckets are forced to flush into the coarse tier.
+	time.Sleep(time.Millisecond * 40)
+	tw.Add(4)
+
+	var coarseSum float64
+	var coarseCount int64
+	tw.ReduceSince(time.Millisecond*300, func(b *Bucket) {
+		coarseSum += b.Sum
+		coarseCount += b.Count
+	})
+
+	assert.Equal(t, float64(7), coarseSum)
+	assert.EqualValues(t, 3, coarseCount)
+}
+
+func TestTieredRollingWindow_ReduceSincePicksFinestCoveringTier(t *testing.T) {
+	tw := NewTieredRollingWindow(
+		Tier{Interval: time.Millisecond * 10, Size: 3},   // covers 30ms
+		Tier{Interval: time.Millisecond * 100, Size: 10}, // covers 1s
+	)
+	tw.Add(9)
+
+	var count int64
+	// 500ms is beyond the 30ms the fine tier covers, so the coarse tier is
+	// selected; the fine tier's live buckets, not yet rolled up, are still
+	// counted in full instead of being skipped.
+	tw.ReduceSince(time.Millisecond*500, func(b *Bucket) {
+		count += b.Count
+	})
+	assert.EqualValues(t, 1, count)
+}